@@ -0,0 +1,122 @@
+package upgrade
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// ArchiveFormat identifies how a downloaded release asset is packaged.
+type ArchiveFormat int
+
+const (
+	// ArchiveAuto sniffs the downloaded asset's content to decide whether
+	// it is a bare binary, a tar.gz, or a zip archive.
+	ArchiveAuto ArchiveFormat = iota
+	// ArchiveNone treats the downloaded asset as the binary itself.
+	ArchiveNone
+	// ArchiveTarGz extracts the binary from a gzip-compressed tarball.
+	ArchiveTarGz
+	// ArchiveZip extracts the binary from a zip archive.
+	ArchiveZip
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// sniffArchiveFormat inspects the leading bytes of a downloaded asset to
+// guess its archive format when ArchiveAuto is requested.
+func sniffArchiveFormat(data []byte) ArchiveFormat {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		return ArchiveTarGz
+	case bytes.HasPrefix(data, zipMagic):
+		return ArchiveZip
+	default:
+		return ArchiveNone
+	}
+}
+
+// extractArchive picks entryName out of a tar.gz or zip archive held in
+// data, returning its contents and its mode bits as recorded in the
+// archive.
+func extractArchive(format ArchiveFormat, data []byte, entryName string) ([]byte, os.FileMode, error) {
+	switch format {
+	case ArchiveTarGz:
+		return extractTarGz(data, entryName)
+	case ArchiveZip:
+		return extractZip(data, entryName)
+	default:
+		return nil, 0, fmt.Errorf("unsupported archive format %d", format)
+	}
+}
+
+func extractTarGz(data []byte, entryName string) ([]byte, os.FileMode, error) {
+	gzr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "unable to open gzip stream")
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "unable to read tar entry")
+		}
+
+		if filepath.Base(hdr.Name) != entryName {
+			continue
+		}
+
+		out, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "unable to read tar entry contents")
+		}
+
+		return out, hdr.FileInfo().Mode().Perm(), nil
+	}
+
+	return nil, 0, fmt.Errorf("entry %q not found in archive", entryName)
+}
+
+func extractZip(data []byte, entryName string) ([]byte, os.FileMode, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "unable to open zip archive")
+	}
+
+	for _, f := range zr.File {
+		if filepath.Base(f.Name) != entryName {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "unable to open zip entry")
+		}
+		defer rc.Close()
+
+		out, err := ioutil.ReadAll(rc)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "unable to read zip entry contents")
+		}
+
+		return out, f.Mode().Perm(), nil
+	}
+
+	return nil, 0, fmt.Errorf("entry %q not found in archive", entryName)
+}
@@ -0,0 +1,125 @@
+package upgrade
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestSniffArchiveFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want ArchiveFormat
+	}{
+		{"gzip magic", []byte{0x1f, 0x8b, 0x08, 0x00}, ArchiveTarGz},
+		{"zip magic", []byte("PK\x03\x04rest-of-the-archive"), ArchiveZip},
+		{"bare binary", []byte("\x7fELF..."), ArchiveNone},
+		{"empty", nil, ArchiveNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffArchiveFormat(tt.data); got != tt.want {
+				t.Errorf("sniffArchiveFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func makeTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	for name, contents := range files {
+		hdr := &tar.Header{
+			Name: name,
+			Mode: 0755,
+			Size: int64(len(contents)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("unable to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			t.Fatalf("unable to write tar entry: %v", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("unable to close tar writer: %v", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("unable to close gzip writer: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func makeZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for name, contents := range files {
+		fw, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("unable to create zip entry: %v", err)
+		}
+		if _, err := fw.Write([]byte(contents)); err != nil {
+			t.Fatalf("unable to write zip entry: %v", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unable to close zip writer: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestExtractTarGz(t *testing.T) {
+	data := makeTarGz(t, map[string]string{
+		"footool_linux_amd64/footool": "#!/bin/sh\necho hi\n",
+		"footool_linux_amd64/LICENSE": "...",
+	})
+
+	out, mode, err := extractTarGz(data, "footool")
+	if err != nil {
+		t.Fatalf("extractTarGz() unexpected error: %v", err)
+	}
+	if string(out) != "#!/bin/sh\necho hi\n" {
+		t.Errorf("extractTarGz() contents = %q", out)
+	}
+	if mode != 0755 {
+		t.Errorf("extractTarGz() mode = %v, want 0755", mode)
+	}
+
+	if _, _, err := extractTarGz(data, "missing"); err == nil {
+		t.Errorf("extractTarGz() expected an error for a missing entry")
+	}
+}
+
+func TestExtractZip(t *testing.T) {
+	data := makeZip(t, map[string]string{
+		"footool_windows_amd64/footool.exe": "MZ...",
+		"footool_windows_amd64/LICENSE":     "...",
+	})
+
+	out, _, err := extractZip(data, "footool.exe")
+	if err != nil {
+		t.Fatalf("extractZip() unexpected error: %v", err)
+	}
+	if string(out) != "MZ..." {
+		t.Errorf("extractZip() contents = %q", out)
+	}
+
+	if _, _, err := extractZip(data, "missing"); err == nil {
+		t.Errorf("extractZip() expected an error for a missing entry")
+	}
+}
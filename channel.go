@@ -0,0 +1,45 @@
+package upgrade
+
+import (
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+// Channel names a release track. The built-in channels apply increasingly
+// strict pre-release filtering to whatever a VersionSource resolves as
+// "available"; anything else (a custom channel string) accepts every
+// version, same as a bare filename suffix always has.
+type Channel string
+
+const (
+	// ChannelNightly accepts every version, pre-release or not.
+	ChannelNightly Channel = "nightly"
+	// ChannelBeta accepts anything except versions tagged "-dev" or
+	// carrying build metadata.
+	ChannelBeta Channel = "beta"
+	// ChannelStable rejects any pre-release or build metadata outright.
+	ChannelStable Channel = "stable"
+)
+
+// Accepts reports whether version is eligible for channel c.
+func (c Channel) Accepts(version semver.Version) bool {
+	switch c {
+	case ChannelNightly:
+		return true
+	case ChannelBeta:
+		if len(version.Build) > 0 {
+			return false
+		}
+		for _, pre := range version.Pre {
+			if strings.Contains(strings.ToLower(pre.String()), "dev") {
+				return false
+			}
+		}
+		return true
+	case ChannelStable:
+		return len(version.Pre) == 0 && len(version.Build) == 0
+	default:
+		return true
+	}
+}
@@ -0,0 +1,41 @@
+package upgrade
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+)
+
+func TestChannelAccepts(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel Channel
+		version string
+		want    bool
+	}{
+		{"nightly accepts release", ChannelNightly, "1.2.3", true},
+		{"nightly accepts prerelease", ChannelNightly, "1.2.3-dev.1", true},
+		{"nightly accepts build metadata", ChannelNightly, "1.2.3+abcdef", true},
+		{"beta accepts release", ChannelBeta, "1.2.3", true},
+		{"beta rejects dev prerelease", ChannelBeta, "1.2.3-dev.1", false},
+		{"beta accepts non-dev prerelease", ChannelBeta, "1.2.3-rc.1", true},
+		{"beta rejects build metadata", ChannelBeta, "1.2.3+abcdef", false},
+		{"stable accepts plain release", ChannelStable, "1.2.3", true},
+		{"stable rejects prerelease", ChannelStable, "1.2.3-rc.1", false},
+		{"stable rejects build metadata", ChannelStable, "1.2.3+abcdef", false},
+		{"unknown channel accepts anything", Channel("custom"), "1.2.3-dev.1", true},
+		{"empty channel accepts anything", Channel(""), "1.2.3-dev.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := semver.Make(tt.version)
+			if err != nil {
+				t.Fatalf("unable to parse version %q: %v", tt.version, err)
+			}
+			if got := tt.channel.Accepts(v); got != tt.want {
+				t.Errorf("Channel(%q).Accepts(%q) = %v, want %v", tt.channel, tt.version, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package upgrade
+
+import "crypto/ed25519"
+
+// DownloadConfig holds the options needed to fetch, and optionally verify,
+// a single release artifact.
+type DownloadConfig struct {
+	Repo    string
+	Version string
+	Tool    string
+
+	// URL, when non-empty, is fetched directly instead of being
+	// synthesized from Repo, Version and Tool. Set by version sources
+	// (e.g. GitHubVersionSource) that resolve a concrete asset URL
+	// themselves.
+	URL string
+
+	// PubKeys, when non-empty, causes the download to be verified against a
+	// signed SHA256SUMS-style manifest before it is trusted. See
+	// UpgradeConfig for a description of the remaining fields.
+	PubKeys        []ed25519.PublicKey
+	HashFileSuffix string
+	SigSuffix      string
+
+	// ArchiveFormat tells the downloader whether the fetched asset is a
+	// bare binary or an archive it must be extracted from. Defaults to
+	// ArchiveAuto, which sniffs the asset's content.
+	ArchiveFormat ArchiveFormat
+	// ArchiveEntry names the file to extract from within the archive.
+	// Defaults to Tool.
+	ArchiveEntry string
+
+	// Progress, when set, is called as the asset downloads so callers can
+	// render a progress bar; see ProgressChannel to drive it from a channel
+	// instead. Use DownloadContext to also make the download cancellable.
+	Progress ProgressFunc
+}
+
+// UpgradeConfig holds the full set of options for an upgrade. It exists so
+// that new options (verification, archive formats, version sources, ...)
+// can be added without changing the signature of every caller of Upgrade.
+type UpgradeConfig struct {
+	Tool           string
+	CurrentVersion string
+	Repo           string
+	FilePrefix     string
+	VersionStable  string
+	// UpgradeForce reinstalls even when the resolved version is the same as
+	// CurrentVersion. It does not permit installing an older version; that
+	// needs AllowDowngrade. Defaults to false.
+	UpgradeForce bool
+
+	// VersionSource, when set, is used to resolve the available version
+	// instead of the default AvailableVersion file convention. See
+	// GitHubVersionSource for a GitHub Releases-backed implementation.
+	VersionSource VersionSource
+
+	// PubKeys holds one or more ed25519 public keys. When non-empty, the
+	// checksum manifest fetched alongside the binary must carry a detached
+	// signature from at least one of them, and the downloaded binary must
+	// match the checksum it contains; the upgrade is aborted otherwise.
+	PubKeys []ed25519.PublicKey
+	// HashFileSuffix names the checksum manifest fetched from the same
+	// repo path as the binary, e.g. "SHA256SUMS". Defaults to "SHA256SUMS".
+	HashFileSuffix string
+	// SigSuffix is appended to HashFileSuffix to locate its detached
+	// signature, e.g. "SHA256SUMS.sig". Defaults to ".sig".
+	SigSuffix string
+
+	// ArchiveFormat and ArchiveEntry behave as on DownloadConfig.
+	ArchiveFormat ArchiveFormat
+	ArchiveEntry  string
+
+	// SkipSmokeTest disables the post-install "<binary> --version" sanity
+	// check (and its automatic rollback on failure). Off by default.
+	SkipSmokeTest bool
+
+	// PreferDelta, when set, makes Upgrade first try a bsdiff patch against
+	// the running executable (see downloadDelta) before falling back to a
+	// full Download.
+	PreferDelta bool
+	// PatchApplier overrides the algorithm used to apply a delta patch.
+	// Defaults to BSDiffApplier.
+	PatchApplier PatchApplier
+
+	// Progress behaves as on DownloadConfig. Use UpgradeContext to also
+	// make the upgrade cancellable.
+	Progress ProgressFunc
+
+	// Channel, when set, rejects a VersionSource-resolved candidate that
+	// doesn't meet its pre-release policy (see Channel.Accepts). It has no
+	// effect on TargetVersion, which is taken as an explicit request.
+	Channel Channel
+	// MinVersion and MaxVersion, when set, pin the resolved version to a
+	// range -- e.g. MaxVersion "2.0.0" freezes a fleet below 2.0 while
+	// still letting it pick up 1.x patches. MinVersion is inclusive and
+	// MaxVersion exclusive. The upgrade is refused, not silently clamped,
+	// when the resolved version falls outside the pin.
+	MinVersion string
+	MaxVersion string
+
+	// TargetVersion, when set, upgrades (or downgrades) straight to this
+	// version instead of whatever the VersionSource resolves as latest. If
+	// VersionSource also implements TargetVersionSource, it is used to
+	// resolve the asset URL for that specific version; otherwise the URL
+	// is synthesized from Repo/Tool/Version as usual.
+	TargetVersion string
+	// AllowDowngrade permits installing a version older than
+	// CurrentVersion; normally that's refused with an error.
+	AllowDowngrade bool
+}
+
+func (cfg DownloadConfig) hashFileSuffix() string {
+	if cfg.HashFileSuffix != "" {
+		return cfg.HashFileSuffix
+	}
+	return "SHA256SUMS"
+}
+
+func (cfg DownloadConfig) sigSuffix() string {
+	if cfg.SigSuffix != "" {
+		return cfg.SigSuffix
+	}
+	return ".sig"
+}
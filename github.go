@@ -0,0 +1,196 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+)
+
+// GitHubVersionSource resolves releases published via GitHub Releases,
+// picking the asset matching the running platform. It implements
+// VersionSource so any project hosted on GitHub can be upgraded from
+// without running a custom version-file server.
+type GitHubVersionSource struct {
+	Owner string
+	Repo  string
+
+	// AllowPrerelease makes a release marked "prerelease" eligible on
+	// ChannelStable or an unset channel; every other channel includes
+	// prereleases whether or not it is set.
+	AllowPrerelease bool
+	// AllowDraft includes the latest release even when it is marked
+	// "draft". Almost never what you want.
+	AllowDraft bool
+	// GoArchExtra appends a platform qualifier (e.g. "v6", "v7") used by
+	// projects that publish separate armv6/armv7 assets.
+	GoArchExtra string
+}
+
+type githubRelease struct {
+	TagName    string        `json:"tag_name"`
+	Prerelease bool          `json:"prerelease"`
+	Draft      bool          `json:"draft"`
+	Assets     []githubAsset `json:"assets"`
+}
+
+type githubAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// Available implements VersionSource. channel is expected to carry one of
+// the Channel constants (cast to string by the caller); an empty channel is
+// treated like ChannelStable, and any other unknown channel lets
+// prereleases through, same as Channel.Accepts does for an unknown channel.
+func (s GitHubVersionSource) Available(ctx context.Context, channel string) (semver.Version, string, error) {
+	// The GitHub REST API's "latest release" endpoint never returns a
+	// draft or prerelease release, no matter what the caller asks for, so
+	// honoring AllowDraft/AllowPrerelease requires listing releases and
+	// picking the first one that matches our policy ourselves.
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases", s.Owner, s.Repo)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return semver.Version{}, "", fmt.Errorf(
+			"while listing releases; unexpected status %s",
+			resp.Status,
+		)
+	}
+
+	var rels []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&rels); err != nil {
+		return semver.Version{}, "", errors.Wrap(err, "unable to decode releases")
+	}
+
+	rel, ok := s.firstEligibleRelease(rels, Channel(channel))
+	if !ok {
+		return semver.Version{}, "", fmt.Errorf("no eligible release found for %s/%s", s.Owner, s.Repo)
+	}
+
+	version, err := semver.Make(strings.TrimPrefix(rel.TagName, "v"))
+	if err != nil {
+		return semver.Version{}, "", errors.Wrap(err, "unable to parse release tag as a version")
+	}
+
+	asset, err := s.assetForRelease(rel)
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+
+	return version, asset, nil
+}
+
+// AvailableTargetVersion implements TargetVersionSource: it looks up the
+// release tagged for version directly, trying the "v"-prefixed tag
+// convention most Go projects publish under before falling back to the
+// bare version string, so UpgradeContext's TargetVersion resolves a real
+// asset URL instead of bypassing the VersionSource entirely.
+func (s GitHubVersionSource) AvailableTargetVersion(ctx context.Context, version semver.Version) (string, error) {
+	rel, err := s.releaseByTag(ctx, "v"+version.String())
+	if err != nil {
+		rel, err = s.releaseByTag(ctx, version.String())
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "unable to find release for target version")
+	}
+
+	return s.assetForRelease(rel)
+}
+
+// releaseByTag fetches the single release published under tag.
+func (s GitHubVersionSource) releaseByTag(ctx context.Context, tag string) (githubRelease, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/tags/%s", s.Owner, s.Repo, tag)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return githubRelease{}, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return githubRelease{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return githubRelease{}, fmt.Errorf(
+			"while getting release %s; unexpected status %s",
+			tag,
+			resp.Status,
+		)
+	}
+
+	var rel githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return githubRelease{}, errors.Wrap(err, "unable to decode release")
+	}
+
+	return rel, nil
+}
+
+// assetForRelease picks the asset matching the running platform out of
+// rel.
+func (s GitHubVersionSource) assetForRelease(rel githubRelease) (string, error) {
+	arch := runtime.GOARCH + s.GoArchExtra
+	for _, asset := range rel.Assets {
+		if hasPlatformToken(asset.Name, runtime.GOOS) && hasPlatformToken(asset.Name, arch) {
+			return asset.BrowserDownloadURL, nil
+		}
+	}
+
+	return "", fmt.Errorf(
+		"no release asset found for %s/%s in %s",
+		runtime.GOOS,
+		arch,
+		rel.TagName,
+	)
+}
+
+// hasPlatformToken reports whether name contains token as a whole
+// "_"/"-"/"."-delimited segment, rather than a bare substring -- so an
+// asset named "tool_linux_arm64.tar.gz" doesn't satisfy a GOARCH of "arm"
+// just because "arm" is a substring of "arm64".
+func hasPlatformToken(name string, token string) bool {
+	for _, seg := range strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	}) {
+		if seg == token {
+			return true
+		}
+	}
+	return false
+}
+
+// firstEligibleRelease returns the newest release in rels (GitHub lists
+// releases newest-first) that satisfies s.AllowDraft/s.AllowPrerelease for
+// the given channel. A prerelease is only filtered out on ChannelStable (or
+// an unset channel, which behaves like ChannelStable); nightly and beta
+// exist specifically to pick those up.
+func (s GitHubVersionSource) firstEligibleRelease(rels []githubRelease, channel Channel) (githubRelease, bool) {
+	for _, rel := range rels {
+		if rel.Draft && !s.AllowDraft {
+			continue
+		}
+		if rel.Prerelease && !s.AllowPrerelease && (channel == "" || channel == ChannelStable) {
+			continue
+		}
+		return rel, true
+	}
+	return githubRelease{}, false
+}
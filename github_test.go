@@ -0,0 +1,200 @@
+package upgrade
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func TestFirstEligibleRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  GitHubVersionSource
+		rels    []githubRelease
+		channel Channel
+		want    string // TagName of the release expected, "" if none
+	}{
+		{
+			name:    "plain release is eligible on stable channel",
+			rels:    []githubRelease{{TagName: "v1.2.3"}},
+			channel: ChannelStable,
+			want:    "v1.2.3",
+		},
+		{
+			name:    "draft is skipped by default",
+			rels:    []githubRelease{{TagName: "v1.2.3", Draft: true}, {TagName: "v1.2.2"}},
+			channel: ChannelStable,
+			want:    "v1.2.2",
+		},
+		{
+			name:    "draft is eligible with AllowDraft",
+			source:  GitHubVersionSource{AllowDraft: true},
+			rels:    []githubRelease{{TagName: "v1.2.3", Draft: true}},
+			channel: ChannelStable,
+			want:    "v1.2.3",
+		},
+		{
+			name:    "prerelease is skipped on stable channel",
+			rels:    []githubRelease{{TagName: "v1.3.0-rc.1", Prerelease: true}, {TagName: "v1.2.3"}},
+			channel: ChannelStable,
+			want:    "v1.2.3",
+		},
+		{
+			name:    "prerelease is skipped on unset channel, same as stable",
+			rels:    []githubRelease{{TagName: "v1.3.0-rc.1", Prerelease: true}, {TagName: "v1.2.3"}},
+			channel: "",
+			want:    "v1.2.3",
+		},
+		{
+			name:    "prerelease is eligible on beta channel",
+			rels:    []githubRelease{{TagName: "v1.3.0-rc.1", Prerelease: true}},
+			channel: ChannelBeta,
+			want:    "v1.3.0-rc.1",
+		},
+		{
+			name:    "prerelease is eligible on nightly channel",
+			rels:    []githubRelease{{TagName: "v1.3.0-rc.1", Prerelease: true}},
+			channel: ChannelNightly,
+			want:    "v1.3.0-rc.1",
+		},
+		{
+			name:    "prerelease is eligible with AllowPrerelease regardless of channel",
+			source:  GitHubVersionSource{AllowPrerelease: true},
+			rels:    []githubRelease{{TagName: "v1.3.0-rc.1", Prerelease: true}},
+			channel: ChannelStable,
+			want:    "v1.3.0-rc.1",
+		},
+		{
+			name:    "draft prerelease is skipped without AllowDraft, even on beta channel",
+			rels:    []githubRelease{{TagName: "v1.3.0-rc.1", Prerelease: true, Draft: true}, {TagName: "v1.2.3"}},
+			channel: ChannelBeta,
+			want:    "v1.2.3",
+		},
+		{
+			name:    "no eligible release",
+			rels:    []githubRelease{{TagName: "v1.2.3", Draft: true}},
+			channel: ChannelStable,
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rel, ok := tt.source.firstEligibleRelease(tt.rels, tt.channel)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("firstEligibleRelease() = %+v, want none", rel)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("firstEligibleRelease() found no release, want %q", tt.want)
+			}
+			if rel.TagName != tt.want {
+				t.Errorf("firstEligibleRelease() = %q, want %q", rel.TagName, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasPlatformToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		asset string
+		token string
+		want  bool
+	}{
+		{"exact segment match", "tool_linux_amd64.tar.gz", "amd64", true},
+		{"exact segment match, no extension", "tool_linux_arm64", "arm64", true},
+		{"arm does not match arm64", "tool_linux_arm64.tar.gz", "arm", false},
+		{"arm64 does not match arm", "tool_linux_arm.tar.gz", "arm64", false},
+		{"386 exact match", "tool_linux_386.zip", "386", true},
+		{"dash-delimited segment", "tool-linux-amd64.zip", "amd64", true},
+		{"no match at all", "tool_windows_amd64.zip", "arm64", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasPlatformToken(tt.asset, tt.token); got != tt.want {
+				t.Errorf("hasPlatformToken(%q, %q) = %v, want %v", tt.asset, tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssetForRelease(t *testing.T) {
+	arch := runtime.GOARCH
+	goos := runtime.GOOS
+	matching := fmt.Sprintf("tool_%s_%s.tar.gz", goos, arch)
+
+	tests := []struct {
+		name    string
+		source  GitHubVersionSource
+		rel     githubRelease
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "matching asset",
+			rel: githubRelease{
+				TagName: "v1.2.3",
+				Assets: []githubAsset{
+					{Name: "tool_windows_arm64.zip", BrowserDownloadURL: "https://example.com/windows"},
+					{Name: matching, BrowserDownloadURL: "https://example.com/match"},
+				},
+			},
+			want: "https://example.com/match",
+		},
+		{
+			name: "no matching asset",
+			rel: githubRelease{
+				TagName: "v1.2.3",
+				Assets: []githubAsset{
+					{Name: "tool_windows_arm64.zip", BrowserDownloadURL: "https://example.com/windows"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "GoArchExtra narrows the match",
+			source: GitHubVersionSource{GoArchExtra: "v7"},
+			rel: githubRelease{
+				TagName: "v1.2.3",
+				Assets: []githubAsset{
+					{Name: fmt.Sprintf("tool_%s_%sv6.tar.gz", goos, arch), BrowserDownloadURL: "https://example.com/v6"},
+					{Name: fmt.Sprintf("tool_%s_%sv7.tar.gz", goos, arch), BrowserDownloadURL: "https://example.com/v7"},
+				},
+			},
+			want: "https://example.com/v7",
+		},
+		{
+			name:   "GoArchExtra with no match errors rather than matching the base arch",
+			source: GitHubVersionSource{GoArchExtra: "v7"},
+			rel: githubRelease{
+				TagName: "v1.2.3",
+				Assets: []githubAsset{
+					{Name: matching, BrowserDownloadURL: "https://example.com/match"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.source.assetForRelease(tt.rel)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("assetForRelease() expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("assetForRelease() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("assetForRelease() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package upgrade
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/kr/binarydist"
+	"github.com/pkg/errors"
+)
+
+// PatchApplier applies a binary patch against the bytes of the currently
+// running executable, producing the bytes of the upgraded one. The default,
+// BSDiffApplier, applies bsdiff-format patches; callers may plug in
+// courgette or another algorithm.
+type PatchApplier interface {
+	Apply(old []byte, patch []byte) ([]byte, error)
+}
+
+// BSDiffApplier applies bsdiff-format patches, as produced by the bsdiff(1)
+// tool or its Go equivalents. It is the default PatchApplier.
+type BSDiffApplier struct{}
+
+// Apply implements PatchApplier.
+func (BSDiffApplier) Apply(old []byte, patch []byte) ([]byte, error) {
+	var out bytes.Buffer
+	if err := binarydist.Patch(bytes.NewReader(old), &out, bytes.NewReader(patch)); err != nil {
+		return nil, errors.Wrap(err, "unable to apply binary patch")
+	}
+	return out.Bytes(), nil
+}
+
+// deltaAssetName is the filename a release is expected to publish a bsdiff
+// patch under for upgrading from "from" to "to" on the running platform.
+func deltaAssetName(tool string, from string, to string) string {
+	return fmt.Sprintf("%s_%s_%s_%s_%s.bsdiff", tool, from, to, runtime.GOOS, runtime.GOARCH)
+}
+
+// downloadDelta attempts a bsdiff-based upgrade of the running executable
+// from curr to avail: it fetches the patch, applies it against the running
+// binary's own bytes, and checks the result against the release's published
+// full-binary checksum. assetURL is whatever cfg.VersionSource resolved for
+// the full binary, if any, and is used (instead of cfg.Repo) to locate the
+// patch and checksum manifest when set. Any failure along the way -- a
+// missing patch, a patch that doesn't apply, or a checksum mismatch -- is
+// returned so the caller can fall back to a full Download.
+func downloadDelta(ctx context.Context, cfg UpgradeConfig, assetURL string, curr semver.Version, avail semver.Version) (string, error) {
+	patchName := deltaAssetName(cfg.Tool, curr.String(), avail.String())
+	patchURL := fmt.Sprintf("%s/%s/%s", cfg.Repo, avail.String(), patchName)
+	if assetURL != "" {
+		patchURL = strings.TrimSuffix(assetURL, path.Base(assetURL)) + patchName
+	}
+
+	patch, err := fetchBytes(ctx, patchURL)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to fetch delta patch")
+	}
+
+	if cfg.Progress != nil {
+		cfg.Progress(Progress{Stage: StageDeltaPatching, BytesDone: int64(len(patch)), BytesTotal: int64(len(patch))})
+	}
+
+	arg0, err := os.Executable()
+	if err != nil {
+		return "", errors.Wrap(err, "unable to find running executable")
+	}
+
+	old, err := ioutil.ReadFile(arg0)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to read running executable")
+	}
+
+	applier := cfg.PatchApplier
+	if applier == nil {
+		applier = BSDiffApplier{}
+	}
+
+	patched, err := applier.Apply(old, patch)
+	if err != nil {
+		return "", err
+	}
+
+	assetName := fmt.Sprintf("%s_%s_%s", cfg.Tool, runtime.GOOS, runtime.GOARCH)
+	sums, err := fetchSums(ctx, DownloadConfig{
+		Repo:           cfg.Repo,
+		Version:        avail.String(),
+		URL:            assetURL,
+		PubKeys:        cfg.PubKeys,
+		HashFileSuffix: cfg.HashFileSuffix,
+		SigSuffix:      cfg.SigSuffix,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "unable to verify patched binary")
+	}
+
+	want, ok := sums[assetName]
+	if !ok {
+		return "", fmt.Errorf("no published checksum for %s to verify patch result against", assetName)
+	}
+
+	sum := sha256.Sum256(patched)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(want, got) {
+		return "", fmt.Errorf("patched binary checksum mismatch: want %s, got %s", want, got)
+	}
+
+	tmp, err := ioutil.TempFile("", cfg.Tool+"_upgrade")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	if err = tmp.Chmod(0755); err != nil {
+		if err.(*os.PathError).Err.Error() != "not supported by windows" {
+			return "", err
+		}
+	}
+
+	if _, err = tmp.Write(patched); err != nil {
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
@@ -0,0 +1,42 @@
+package upgrade
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/kr/binarydist"
+)
+
+func TestDeltaAssetName(t *testing.T) {
+	got := deltaAssetName("mytool", "1.2.3", "1.3.0")
+	want := fmt.Sprintf("mytool_1.2.3_1.3.0_%s_%s.bsdiff", runtime.GOOS, runtime.GOARCH)
+	if got != want {
+		t.Errorf("deltaAssetName() = %q, want %q", got, want)
+	}
+}
+
+func TestBSDiffApplierAppliesPatch(t *testing.T) {
+	old := []byte("the quick brown fox jumps over the lazy dog")
+	new := []byte("the quick brown fox leaps over the lazy dogs")
+
+	var patch bytes.Buffer
+	if err := binarydist.Diff(bytes.NewReader(old), bytes.NewReader(new), &patch); err != nil {
+		t.Fatalf("binarydist.Diff() err = %v", err)
+	}
+
+	got, err := (BSDiffApplier{}).Apply(old, patch.Bytes())
+	if err != nil {
+		t.Fatalf("Apply() err = %v", err)
+	}
+	if !bytes.Equal(got, new) {
+		t.Errorf("Apply() = %q, want %q", got, new)
+	}
+}
+
+func TestBSDiffApplierRejectsCorruptPatch(t *testing.T) {
+	if _, err := (BSDiffApplier{}).Apply([]byte("old"), []byte("not a real patch")); err == nil {
+		t.Error("Apply() with a corrupt patch: got nil error, want non-nil")
+	}
+}
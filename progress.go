@@ -0,0 +1,57 @@
+package upgrade
+
+import "io"
+
+// Stage identifies which part of an upgrade a Progress event describes.
+type Stage string
+
+const (
+	// StageDownloading covers fetching the release asset itself.
+	StageDownloading Stage = "downloading"
+	// StageDeltaPatching covers fetching and applying a bsdiff patch.
+	StageDeltaPatching Stage = "delta_patching"
+)
+
+// Progress describes how far an in-progress download has gotten.
+// BytesTotal is 0 when the server didn't send a Content-Length.
+type Progress struct {
+	Stage      Stage
+	BytesDone  int64
+	BytesTotal int64
+}
+
+// ProgressFunc receives Progress events as a download proceeds.
+type ProgressFunc func(Progress)
+
+// ProgressChannel adapts ch into a ProgressFunc, for callers that would
+// rather range over a channel than supply a callback. Events are dropped,
+// not blocked on, if ch isn't being drained.
+func ProgressChannel(ch chan<- Progress) ProgressFunc {
+	return func(p Progress) {
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+// countingReader wraps r, invoking report with a running byte count after
+// every Read that returns data; zero-byte reads are not reported.
+type countingReader struct {
+	r      io.Reader
+	stage  Stage
+	total  int64
+	done   int64
+	report ProgressFunc
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.done += int64(n)
+		if c.report != nil {
+			c.report(Progress{Stage: c.stage, BytesDone: c.done, BytesTotal: c.total})
+		}
+	}
+	return n, err
+}
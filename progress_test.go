@@ -0,0 +1,104 @@
+package upgrade
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCountingReaderReportsRunningTotal(t *testing.T) {
+	var events []Progress
+	report := func(p Progress) { events = append(events, p) }
+
+	r := &countingReader{
+		r:      bytes.NewReader([]byte("hello, world")),
+		stage:  StageDownloading,
+		total:  12,
+		report: report,
+	}
+
+	buf := make([]byte, 5)
+	n, err := r.Read(buf)
+	if err != nil || n != 5 {
+		t.Fatalf("Read() = %d, %v; want 5, nil", n, err)
+	}
+	n, err = r.Read(buf)
+	if err != nil || n != 5 {
+		t.Fatalf("Read() = %d, %v; want 5, nil", n, err)
+	}
+
+	want := []Progress{
+		{Stage: StageDownloading, BytesDone: 5, BytesTotal: 12},
+		{Stage: StageDownloading, BytesDone: 10, BytesTotal: 12},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		if events[i] != w {
+			t.Errorf("event[%d] = %+v, want %+v", i, events[i], w)
+		}
+	}
+}
+
+func TestCountingReaderSkipsReportOnZeroRead(t *testing.T) {
+	calls := 0
+	r := &countingReader{
+		r:      bytes.NewReader(nil),
+		stage:  StageDownloading,
+		total:  0,
+		report: func(Progress) { calls++ },
+	}
+
+	if _, err := r.Read(make([]byte, 4)); err != io.EOF {
+		t.Fatalf("Read() err = %v, want io.EOF", err)
+	}
+	if calls != 0 {
+		t.Errorf("report called %d times on a zero-byte read, want 0", calls)
+	}
+}
+
+func TestCountingReaderToleratesNilReport(t *testing.T) {
+	r := &countingReader{r: bytes.NewReader([]byte("x")), stage: StageDownloading, total: 1}
+	if _, err := ioutil.ReadAll(r); err != nil {
+		t.Fatalf("ReadAll() err = %v", err)
+	}
+}
+
+func TestProgressChannelDeliversWhenDrained(t *testing.T) {
+	ch := make(chan Progress, 1)
+	fn := ProgressChannel(ch)
+
+	fn(Progress{Stage: StageDownloading, BytesDone: 1, BytesTotal: 2})
+
+	select {
+	case p := <-ch:
+		want := Progress{Stage: StageDownloading, BytesDone: 1, BytesTotal: 2}
+		if p != want {
+			t.Errorf("got %+v, want %+v", p, want)
+		}
+	default:
+		t.Fatal("expected a buffered event, got none")
+	}
+}
+
+func TestProgressChannelDropsWhenUndrained(t *testing.T) {
+	ch := make(chan Progress, 1)
+	fn := ProgressChannel(ch)
+
+	fn(Progress{BytesDone: 1})
+	// The channel's single buffer slot is now full; a second send must be
+	// dropped rather than block.
+	fn(Progress{BytesDone: 2})
+
+	got := <-ch
+	if got.BytesDone != 1 {
+		t.Errorf("got %+v, want the first event to have been kept", got)
+	}
+	select {
+	case extra := <-ch:
+		t.Fatalf("expected channel to be empty, got %+v", extra)
+	default:
+	}
+}
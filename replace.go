@@ -0,0 +1,71 @@
+package upgrade
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// replaceExecutable atomically replaces dst with the contents of src. It
+// first copies src into a temp file alongside dst -- guaranteeing the
+// eventual rename stays on dst's filesystem even when src (e.g. a download
+// landed in the system temp dir) lives on a different one -- and fsyncs it
+// before handing off to the platform-specific atomicReplace to swap it into
+// place. On Windows, atomicReplace additionally copes with dst being the
+// locked, currently-running executable.
+func replaceExecutable(dst string, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrap(err, "unable to open replacement binary")
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return errors.Wrap(err, "unable to stat replacement binary")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(dst), filepath.Base(dst)+".new")
+	if err != nil {
+		return errors.Wrap(err, "unable to create replacement temp file")
+	}
+	tmpfn := tmp.Name()
+	defer os.Remove(tmpfn)
+
+	if err = tmp.Chmod(fi.Mode()); err != nil {
+		if err.(*os.PathError).Err.Error() != "not supported by windows" {
+			tmp.Close()
+			return errors.Wrap(err, "unable to set replacement binary mode")
+		}
+	}
+
+	if _, err = io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "unable to write replacement binary")
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "unable to fsync replacement binary")
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "unable to close replacement binary")
+	}
+
+	return atomicReplace(dst, tmpfn)
+}
+
+// smokeTestVersion runs path --version as a basic sanity check that a
+// freshly installed binary actually starts before we commit to it.
+func smokeTestVersion(path string) error {
+	if err := exec.Command(path, "--version").Run(); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s --version failed", path))
+	}
+	return nil
+}
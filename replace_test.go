@@ -0,0 +1,91 @@
+package upgrade
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplaceExecutableCopiesSrcOverDst(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replace_test")
+	if err != nil {
+		t.Fatalf("TempDir() err = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "tool")
+	if err := ioutil.WriteFile(dst, []byte("old"), 0644); err != nil {
+		t.Fatalf("WriteFile(dst) err = %v", err)
+	}
+
+	srcDir, err := ioutil.TempDir("", "replace_test_src")
+	if err != nil {
+		t.Fatalf("TempDir() err = %v", err)
+	}
+	defer os.RemoveAll(srcDir)
+
+	src := filepath.Join(srcDir, "tool.new")
+	if err := ioutil.WriteFile(src, []byte("new binary contents"), 0755); err != nil {
+		t.Fatalf("WriteFile(src) err = %v", err)
+	}
+
+	if err := replaceExecutable(dst, src); err != nil {
+		t.Fatalf("replaceExecutable() err = %v", err)
+	}
+
+	got, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("ReadFile(dst) err = %v", err)
+	}
+	if string(got) != "new binary contents" {
+		t.Errorf("dst contents = %q, want %q", got, "new binary contents")
+	}
+}
+
+func TestReplaceExecutableUsesSrcMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replace_test_mode")
+	if err != nil {
+		t.Fatalf("TempDir() err = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "tool")
+	if err := ioutil.WriteFile(dst, []byte("old"), 0644); err != nil {
+		t.Fatalf("WriteFile(dst) err = %v", err)
+	}
+
+	src := filepath.Join(dir, "tool.new")
+	if err := ioutil.WriteFile(src, []byte("new"), 0755); err != nil {
+		t.Fatalf("WriteFile(src) err = %v", err)
+	}
+
+	if err := replaceExecutable(dst, src); err != nil {
+		t.Fatalf("replaceExecutable() err = %v", err)
+	}
+
+	fi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatalf("Stat(dst) err = %v", err)
+	}
+	if fi.Mode().Perm() != 0755 {
+		t.Errorf("dst mode = %v, want 0755 (src's mode)", fi.Mode().Perm())
+	}
+}
+
+func TestReplaceExecutableMissingSrc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replace_test_missing")
+	if err != nil {
+		t.Fatalf("TempDir() err = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "tool")
+	if err := ioutil.WriteFile(dst, []byte("old"), 0644); err != nil {
+		t.Fatalf("WriteFile(dst) err = %v", err)
+	}
+
+	if err := replaceExecutable(dst, filepath.Join(dir, "does-not-exist")); err == nil {
+		t.Error("replaceExecutable() with a missing src: got nil error, want non-nil")
+	}
+}
@@ -0,0 +1,12 @@
+// +build !windows
+
+package upgrade
+
+import "os"
+
+// atomicReplace renames src over dst. rename(2) within the same filesystem
+// is already atomic, and Unix happily renames a file out from under the
+// process currently executing it, so no special handling is needed here.
+func atomicReplace(dst string, src string) error {
+	return os.Rename(src, dst)
+}
@@ -0,0 +1,55 @@
+// +build windows
+
+package upgrade
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+// atomicReplace swaps src into dst via MoveFileEx, which can atomically
+// replace an existing file. When dst is the running executable, Windows
+// sometimes refuses that direct replace with a sharing violation; in that
+// case we fall back to the classic rename-to-.old dance (move dst aside,
+// move src into dst's place) and schedule the orphaned .old file for
+// deletion on next reboot, since Windows won't let us delete a binary that
+// is still mapped into a running process.
+func atomicReplace(dst string, src string) error {
+	dstPtr, err := windows.UTF16PtrFromString(dst)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode destination path")
+	}
+	srcPtr, err := windows.UTF16PtrFromString(src)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode source path")
+	}
+
+	flags := uint32(windows.MOVEFILE_REPLACE_EXISTING | windows.MOVEFILE_WRITE_THROUGH)
+	if err := windows.MoveFileEx(srcPtr, dstPtr, flags); err == nil {
+		return nil
+	}
+
+	old := dst + ".old"
+	_ = os.Remove(old)
+
+	oldPtr, err := windows.UTF16PtrFromString(old)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode backup path")
+	}
+	if err := windows.MoveFileEx(dstPtr, oldPtr, windows.MOVEFILE_REPLACE_EXISTING); err != nil {
+		return errors.Wrap(err, "unable to move locked executable aside")
+	}
+	if err := windows.MoveFileEx(srcPtr, dstPtr, flags); err != nil {
+		return errors.Wrap(err, "unable to move replacement into place")
+	}
+
+	// The .old file is still mapped into this (or another running) process,
+	// so it can't be deleted now; let Windows clean it up on next boot.
+	if err := windows.MoveFileEx(oldPtr, nil, windows.MOVEFILE_DELAY_UNTIL_REBOOT); err != nil {
+		return errors.Wrap(err, "unable to schedule cleanup of the old executable")
+	}
+
+	return nil
+}
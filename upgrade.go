@@ -1,6 +1,8 @@
 package upgrade
 
 import (
+	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,7 +14,6 @@ import (
 
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
-	"github.com/termie/go-shutil"
 )
 
 func CurrentVersion(version string) (semver.Version, error) {
@@ -20,12 +21,23 @@ func CurrentVersion(version string) (semver.Version, error) {
 }
 
 func AvailableVersion(repo string, filePrefix string, channel string) (semver.Version, error) {
-	resp, err := http.Get(
-		fmt.Sprintf("%s/%s%s", repo, filePrefix, channel),
+	return AvailableVersionContext(context.Background(), repo, filePrefix, channel)
+}
+
+// AvailableVersionContext behaves as AvailableVersion, but honors ctx
+// cancellation/deadlines on the underlying request.
+func AvailableVersionContext(ctx context.Context, repo string, filePrefix string, channel string) (semver.Version, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet, fmt.Sprintf("%s/%s%s", repo, filePrefix, channel), nil,
 	)
 	if err != nil {
 		return semver.Version{}, err
 	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return semver.Version{}, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
@@ -46,6 +58,12 @@ func AvailableVersion(repo string, filePrefix string, channel string) (semver.Ve
 // the repository. It uses a caching strategy to avoid costly
 // checks with every run.
 func CheckAndNotifyIfOutOfDate(tool string, currentVersion string, repo string, filePrefix string, versionStable string, hoursToCheckForUpdate float64, upgradeCommand string) (bool, error) {
+	return CheckAndNotifyIfOutOfDateContext(context.Background(), tool, currentVersion, repo, filePrefix, versionStable, hoursToCheckForUpdate, upgradeCommand)
+}
+
+// CheckAndNotifyIfOutOfDateContext behaves as CheckAndNotifyIfOutOfDate, but
+// honors ctx cancellation/deadlines on the underlying version check.
+func CheckAndNotifyIfOutOfDateContext(ctx context.Context, tool string, currentVersion string, repo string, filePrefix string, versionStable string, hoursToCheckForUpdate float64, upgradeCommand string) (bool, error) {
 	fn := filepath.Join(GetHome(), "."+tool+"-version-check")
 	file, err := os.OpenFile(fn, os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
@@ -79,7 +97,7 @@ func CheckAndNotifyIfOutOfDate(tool string, currentVersion string, repo string,
 	if err != nil {
 		return false, err
 	}
-	avail, err := AvailableVersion(repo, filePrefix, versionStable)
+	avail, err := AvailableVersionContext(ctx, repo, filePrefix, versionStable)
 	if err != nil {
 		return false, err
 	}
@@ -93,8 +111,27 @@ func CheckAndNotifyIfOutOfDate(tool string, currentVersion string, repo string,
 }
 
 func Download(repo string, version string, tool string) (string, error) {
+	return DownloadWithConfig(DownloadConfig{Repo: repo, Version: version, Tool: tool})
+}
+
+// DownloadWithConfig behaves as DownloadContext with a background context.
+func DownloadWithConfig(cfg DownloadConfig) (string, error) {
+	return DownloadContext(context.Background(), cfg)
+}
+
+// DownloadContext fetches the release asset described by cfg into a new
+// temp file and returns its path, honoring ctx's cancellation/deadline and
+// reporting cfg.Progress as the response body streams in. When cfg.PubKeys
+// is non-empty, the raw asset's SHA-256 is checked against a signed
+// checksum manifest fetched from the same repo path before the temp file
+// is handed back; the upgrade is aborted with an error if either the
+// signature or the checksum fails to verify. When cfg.ArchiveFormat
+// indicates (or sniffing detects) a tar.gz or zip archive, cfg.ArchiveEntry
+// (default: cfg.Tool) is extracted from it and its mode bits are preserved
+// on the temp file.
+func DownloadContext(ctx context.Context, cfg DownloadConfig) (string, error) {
 	// now that we have the version, create the temp file and set perms
-	tmp, err := ioutil.TempFile("", tool+"_upgrade")
+	tmp, err := ioutil.TempFile("", cfg.Tool+"_upgrade")
 	if err != nil {
 		return "", err
 	}
@@ -113,16 +150,20 @@ func Download(repo string, version string, tool string) (string, error) {
 		}
 	}()
 
+	assetName := fmt.Sprintf("%s_%s_%s", cfg.Tool, runtime.GOOS, runtime.GOARCH)
+	downloadURL := fmt.Sprintf("%s/%s/%s", cfg.Repo, cfg.Version, assetName)
+	if cfg.URL != "" {
+		downloadURL = cfg.URL
+		assetName = filepath.Base(cfg.URL)
+	}
+
 	// get the fresh bits
-	resp, err := http.Get(
-		fmt.Sprintf("%s/%s/%s_%s_%s",
-			repo,
-			version,
-			tool,
-			runtime.GOOS,
-			runtime.GOARCH,
-		),
-	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -135,7 +176,55 @@ func Download(repo string, version string, tool string) (string, error) {
 		)
 	}
 
-	if _, err := io.Copy(tmp, resp.Body); err != nil {
+	body := io.Reader(resp.Body)
+	if cfg.Progress != nil {
+		total := resp.ContentLength
+		if total < 0 {
+			// Unknown (e.g. chunked) response length; Progress.BytesTotal
+			// documents 0, not -1, as the "unknown" signal.
+			total = 0
+		}
+		body = &countingReader{r: resp.Body, stage: StageDownloading, total: total, report: cfg.Progress}
+	}
+
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+
+	if len(cfg.PubKeys) > 0 {
+		sum := sha256.Sum256(raw)
+		if err = verifyChecksum(ctx, cfg, assetName, sum[:]); err != nil {
+			return "", err
+		}
+	}
+
+	format := cfg.ArchiveFormat
+	if format == ArchiveAuto {
+		format = sniffArchiveFormat(raw)
+	}
+
+	payload := raw
+	if format != ArchiveNone {
+		entry := cfg.ArchiveEntry
+		if entry == "" {
+			entry = cfg.Tool
+		}
+
+		var mode os.FileMode
+		if payload, mode, err = extractArchive(format, raw, entry); err != nil {
+			return "", errors.Wrap(err, "unable to extract archive")
+		}
+		if mode != 0 {
+			if err = tmp.Chmod(mode); err != nil {
+				if err.(*os.PathError).Err.Error() != "not supported by windows" {
+					return "", err
+				}
+			}
+		}
+	}
+
+	if _, err = tmp.Write(payload); err != nil {
 		return "", err
 	}
 
@@ -162,17 +251,103 @@ func RemoveBackup() (string, string, error) {
 }
 
 func Upgrade(tool string, currentVersion string, repo string, filePrefix string, versionStable string, upgradeForce *bool) error {
-	curr, err := CurrentVersion(currentVersion)
+	var force bool
+	if upgradeForce != nil {
+		force = *upgradeForce
+	}
+	return UpgradeWithConfig(UpgradeConfig{
+		Tool:           tool,
+		CurrentVersion: currentVersion,
+		Repo:           repo,
+		FilePrefix:     filePrefix,
+		VersionStable:  versionStable,
+		UpgradeForce:   force,
+	})
+}
+
+// UpgradeWithConfig behaves as UpgradeContext with a background context.
+func UpgradeWithConfig(cfg UpgradeConfig) error {
+	return UpgradeContext(context.Background(), cfg)
+}
+
+// UpgradeContext performs the same in-place upgrade as Upgrade, with
+// additional options (signed checksum verification, archive extraction,
+// pluggable VersionSource, progress reporting, see UpgradeConfig) that
+// would otherwise require changing Upgrade's signature, and honors ctx's
+// cancellation/deadline for every network request it makes along the way.
+// Note that once the running executable has started being replaced,
+// cancellation is no longer honored -- that step always runs to completion
+// or rolls back, to avoid leaving the install in a half-upgraded state.
+func UpgradeContext(ctx context.Context, cfg UpgradeConfig) error {
+	curr, err := CurrentVersion(cfg.CurrentVersion)
 	if err != nil {
 		return errors.Wrap(err, "unable to get current version")
 	}
-	avail, err := AvailableVersion(repo, filePrefix, versionStable)
-	if err != nil {
-		return errors.Wrap(err, "unable to get available version")
+
+	var avail semver.Version
+	var assetURL string
+	if cfg.TargetVersion != "" {
+		if avail, err = CurrentVersion(cfg.TargetVersion); err != nil {
+			return errors.Wrap(err, "invalid TargetVersion")
+		}
+		if ts, ok := cfg.VersionSource.(TargetVersionSource); ok {
+			if assetURL, err = ts.AvailableTargetVersion(ctx, avail); err != nil {
+				return errors.Wrap(err, "unable to resolve TargetVersion")
+			}
+		}
+	} else {
+		source := cfg.VersionSource
+		if source == nil {
+			source = FileVersionSource{Repo: cfg.Repo, FilePrefix: cfg.FilePrefix}
+		}
+
+		// VersionStable is FileVersionSource's legacy free-form filename
+		// suffix; Channel is the typed replacement a VersionSource should
+		// use to decide its own pre-release/draft policy. Prefer Channel
+		// once a caller has opted into it.
+		channel := cfg.VersionStable
+		if cfg.Channel != "" {
+			channel = string(cfg.Channel)
+		}
+		if avail, assetURL, err = source.Available(ctx, channel); err != nil {
+			return errors.Wrap(err, "unable to get available version")
+		}
+
+		if cfg.Channel != "" && !cfg.Channel.Accepts(avail) {
+			return fmt.Errorf("available version %s is not permitted on channel %q", avail, cfg.Channel)
+		}
+	}
+
+	if cfg.MinVersion != "" {
+		min, err := semver.Make(cfg.MinVersion)
+		if err != nil {
+			return errors.Wrap(err, "invalid MinVersion")
+		}
+		if avail.LT(min) {
+			return fmt.Errorf("available version %s is below the configured MinVersion %s", avail, min)
+		}
+	}
+	if cfg.MaxVersion != "" {
+		max, err := semver.Make(cfg.MaxVersion)
+		if err != nil {
+			return errors.Wrap(err, "invalid MaxVersion")
+		}
+		if avail.GTE(max) {
+			return fmt.Errorf("available version %s is at or above the configured MaxVersion %s", avail, max)
+		}
 	}
 
-	if !*upgradeForce && curr.GTE(avail) {
-		fmt.Printf("%s is up-to-date. Go forth and be awesome!\n", tool)
+	if avail.LT(curr) {
+		if !cfg.AllowDowngrade {
+			return fmt.Errorf(
+				"%s is at version %s; refusing to downgrade to %s (set AllowDowngrade to override)",
+				cfg.Tool,
+				curr,
+				avail,
+			)
+		}
+	} else if !cfg.UpgradeForce && curr.GTE(avail) {
+		fmt.Printf("%s is up-to-date. Go forth and be awesome!\n", cfg.Tool)
 		return nil
 	}
 
@@ -182,29 +357,53 @@ func Upgrade(tool string, currentVersion string, repo string, filePrefix string,
 	}
 
 	fmt.Println("upgrading", arg0, "to", avail)
-	tmp, err := Download(repo, avail.String(), tool)
-	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf(
-			"unable to upgrade %s",
-			tool,
-		))
+
+	var tmp string
+	if cfg.PreferDelta {
+		if tmp, err = downloadDelta(ctx, cfg, assetURL, curr, avail); err != nil {
+			fmt.Fprintf(os.Stderr, "delta upgrade unavailable (%s); falling back to full download\n", err)
+			tmp = ""
+		}
+	}
+
+	if tmp == "" {
+		tmp, err = DownloadContext(ctx, DownloadConfig{
+			Repo:           cfg.Repo,
+			Version:        avail.String(),
+			Tool:           cfg.Tool,
+			URL:            assetURL,
+			PubKeys:        cfg.PubKeys,
+			HashFileSuffix: cfg.HashFileSuffix,
+			SigSuffix:      cfg.SigSuffix,
+			ArchiveFormat:  cfg.ArchiveFormat,
+			ArchiveEntry:   cfg.ArchiveEntry,
+			Progress:       cfg.Progress,
+		})
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf(
+				"unable to upgrade %s",
+				cfg.Tool,
+			))
+		}
 	}
 
+	// arg0 and backup are always in the same directory (backup is just
+	// arg0 + "~"), so a plain rename is already atomic here; no need for
+	// replaceExecutable's cross-filesystem-safe copy dance.
 	err = os.Rename(arg0, backup)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf(
 			"unable to upgrade %s in-place; move %s to %s",
-			tool,
+			cfg.Tool,
 			tmp,
 			os.Args[0],
 		))
 	}
 
-	_, err = shutil.Copy(tmp, arg0, false)
+	err = replaceExecutable(arg0, tmp)
 	if err != nil {
-		err = os.Rename(backup, arg0)
-		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf(
+		if rerr := os.Rename(backup, arg0); rerr != nil {
+			return errors.Wrap(rerr, fmt.Sprintf(
 				"upgrade failed and unable to recover from backup;"+
 					" move %s to %s",
 				tmp,
@@ -213,7 +412,7 @@ func Upgrade(tool string, currentVersion string, repo string, filePrefix string,
 		}
 		return errors.Wrap(err, fmt.Sprintf(
 			"unable to upgrade %s in-place; move %s to %s",
-			tool,
+			cfg.Tool,
 			tmp,
 			os.Args[0],
 		))
@@ -227,5 +426,14 @@ func Upgrade(tool string, currentVersion string, repo string, filePrefix string,
 		))
 	}
 
+	if !cfg.SkipSmokeTest {
+		if err = smokeTestVersion(arg0); err != nil {
+			if rerr := os.Rename(backup, arg0); rerr != nil {
+				return errors.Wrap(rerr, "new binary failed its post-install smoke test and unable to recover from backup")
+			}
+			return errors.Wrap(err, "new binary failed its post-install smoke test; rolled back to previous version")
+		}
+	}
+
 	return nil
 }
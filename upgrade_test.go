@@ -0,0 +1,155 @@
+package upgrade
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver"
+)
+
+// fakeVersionSource is a minimal VersionSource and TargetVersionSource for
+// exercising UpgradeContext's resolution logic without any HTTP involved.
+type fakeVersionSource struct {
+	version  string
+	assetURL string
+	err      error
+
+	targetAssetURL string
+	targetErr      error
+	targetCalled   bool
+}
+
+func (s *fakeVersionSource) Available(ctx context.Context, channel string) (semver.Version, string, error) {
+	if s.err != nil {
+		return semver.Version{}, "", s.err
+	}
+	v, err := semver.Make(s.version)
+	return v, s.assetURL, err
+}
+
+func (s *fakeVersionSource) AvailableTargetVersion(ctx context.Context, version semver.Version) (string, error) {
+	s.targetCalled = true
+	if s.targetErr != nil {
+		return "", s.targetErr
+	}
+	return s.targetAssetURL, nil
+}
+
+func TestUpgradeContextMinVersion(t *testing.T) {
+	err := UpgradeContext(context.Background(), UpgradeConfig{
+		Tool:           "tool",
+		CurrentVersion: "1.0.0",
+		VersionSource:  &fakeVersionSource{version: "1.5.0"},
+		MinVersion:     "2.0.0",
+	})
+	if err == nil {
+		t.Fatal("UpgradeContext() = nil, want an error rejecting a resolved version below MinVersion")
+	}
+	if !strings.Contains(err.Error(), "below the configured MinVersion") {
+		t.Errorf("UpgradeContext() err = %q, want it to mention MinVersion", err)
+	}
+}
+
+func TestUpgradeContextMaxVersion(t *testing.T) {
+	err := UpgradeContext(context.Background(), UpgradeConfig{
+		Tool:           "tool",
+		CurrentVersion: "1.0.0",
+		VersionSource:  &fakeVersionSource{version: "3.0.0"},
+		MaxVersion:     "2.0.0",
+	})
+	if err == nil {
+		t.Fatal("UpgradeContext() = nil, want an error rejecting a resolved version above MaxVersion")
+	}
+	if !strings.Contains(err.Error(), "above the configured MaxVersion") {
+		t.Errorf("UpgradeContext() err = %q, want it to mention MaxVersion", err)
+	}
+}
+
+func TestUpgradeContextAllowDowngrade(t *testing.T) {
+	cfg := UpgradeConfig{
+		Tool:           "tool",
+		CurrentVersion: "2.0.0",
+		VersionSource:  &fakeVersionSource{version: "1.0.0"},
+	}
+
+	err := UpgradeContext(context.Background(), cfg)
+	if err == nil {
+		t.Fatal("UpgradeContext() = nil, want a refusal to downgrade without AllowDowngrade")
+	}
+	if !strings.Contains(err.Error(), "refusing to downgrade") {
+		t.Errorf("UpgradeContext() err = %q, want it to mention the downgrade refusal", err)
+	}
+
+	cfg.AllowDowngrade = true
+	cfg.VersionSource = &fakeVersionSource{version: "1.0.0"}
+	// AllowDowngrade lets the version check pass; the upgrade still fails
+	// downstream trying to reach the network, but crucially not with the
+	// "refusing to downgrade" error.
+	if err := UpgradeContext(context.Background(), cfg); err == nil || strings.Contains(err.Error(), "refusing to downgrade") {
+		t.Errorf("UpgradeContext() with AllowDowngrade err = %v, want it to pass the downgrade check and fail later", err)
+	}
+}
+
+func TestUpgradeContextChannelRejectsResolvedVersion(t *testing.T) {
+	err := UpgradeContext(context.Background(), UpgradeConfig{
+		Tool:           "tool",
+		CurrentVersion: "1.0.0",
+		VersionSource:  &fakeVersionSource{version: "1.1.0-rc.1"},
+		Channel:        ChannelStable,
+	})
+	if err == nil {
+		t.Fatal("UpgradeContext() = nil, want an error: a pre-release isn't permitted on ChannelStable")
+	}
+	if !strings.Contains(err.Error(), "not permitted on channel") {
+		t.Errorf("UpgradeContext() err = %q, want it to mention the channel rejection", err)
+	}
+}
+
+func TestUpgradeContextChannelAcceptsResolvedVersion(t *testing.T) {
+	source := &fakeVersionSource{version: "1.1.0-rc.1"}
+	err := UpgradeContext(context.Background(), UpgradeConfig{
+		Tool:           "tool",
+		CurrentVersion: "1.0.0",
+		VersionSource:  source,
+		Channel:        ChannelBeta,
+	})
+	// A beta channel accepts the resolved pre-release, so the upgrade
+	// proceeds past Channel.Accepts and fails downstream trying to reach
+	// the network -- which is the signal we want: rejection never fired.
+	if err == nil || strings.Contains(err.Error(), "not permitted on channel") {
+		t.Errorf("UpgradeContext() err = %v, want it to pass the channel check and fail later", err)
+	}
+}
+
+func TestUpgradeContextTargetVersionResolvesThroughVersionSource(t *testing.T) {
+	source := &fakeVersionSource{targetAssetURL: "https://example.com/tool_1.0.0"}
+	err := UpgradeContext(context.Background(), UpgradeConfig{
+		Tool:           "tool",
+		CurrentVersion: "1.0.0",
+		VersionSource:  source,
+		TargetVersion:  "1.0.0",
+	})
+	if !source.targetCalled {
+		t.Error("UpgradeContext() never called AvailableTargetVersion on the VersionSource")
+	}
+	// TargetVersion equals CurrentVersion, so the upgrade short-circuits as
+	// already up-to-date instead of attempting a download.
+	if err != nil {
+		t.Errorf("UpgradeContext() err = %v, want nil (already at the target version)", err)
+	}
+}
+
+func TestUpgradeContextTargetVersionInvalid(t *testing.T) {
+	err := UpgradeContext(context.Background(), UpgradeConfig{
+		Tool:           "tool",
+		CurrentVersion: "1.0.0",
+		TargetVersion:  "not-a-version",
+	})
+	if err == nil {
+		t.Fatal("UpgradeContext() = nil, want an error for an invalid TargetVersion")
+	}
+	if !strings.Contains(err.Error(), "invalid TargetVersion") {
+		t.Errorf("UpgradeContext() err = %q, want it to mention TargetVersion", err)
+	}
+}
@@ -0,0 +1,141 @@
+package upgrade
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseSHA256Sums parses the contents of a sha256sum(1)-style manifest
+// ("<hex hash>  <filename>" per line) into a map of filename to lowercase
+// hex-encoded hash.
+func ParseSHA256Sums(data []byte) (map[string]string, error) {
+	sums := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("malformed checksum line: %q", line)
+		}
+
+		// sha256sum(1) marks binary mode entries with a leading "*" on the
+		// filename; it has no bearing on the hash itself.
+		name := strings.TrimPrefix(fields[len(fields)-1], "*")
+		sums[name] = strings.ToLower(fields[0])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "unable to parse checksums")
+	}
+
+	return sums, nil
+}
+
+// VerifyDetachedSignature checks sig as an ed25519 detached signature of
+// data against at least one of pubkeys, returning an error if none match.
+func VerifyDetachedSignature(data []byte, sig []byte, pubkeys []ed25519.PublicKey) error {
+	if len(pubkeys) == 0 {
+		return errors.New("no public keys configured to verify signature")
+	}
+
+	for _, pk := range pubkeys {
+		if ed25519.Verify(pk, data, sig) {
+			return nil
+		}
+	}
+
+	return errors.New("signature does not match any configured public key")
+}
+
+// fetchBytes is a small http.Get+ioutil.ReadAll helper shared by the
+// checksum and signature fetches below.
+func fetchBytes(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"while getting %s; unexpected status %s",
+			url,
+			resp.Status,
+		)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// fetchSums fetches and parses the checksum manifest for cfg's release. If
+// cfg.PubKeys is non-empty, its detached signature is fetched and verified
+// first; otherwise the manifest is trusted as-is (callers that require
+// authenticity, not just integrity, must set PubKeys).
+func fetchSums(ctx context.Context, cfg DownloadConfig) (map[string]string, error) {
+	sumsURL := fmt.Sprintf("%s/%s/%s", cfg.Repo, cfg.Version, cfg.hashFileSuffix())
+	if cfg.URL != "" {
+		// A VersionSource (e.g. GitHubVersionSource) resolved a concrete
+		// asset URL; cfg.Repo has no defined meaning for it, so the
+		// manifest lives alongside the asset instead.
+		sumsURL = strings.TrimSuffix(cfg.URL, path.Base(cfg.URL)) + cfg.hashFileSuffix()
+	}
+
+	sumsData, err := fetchBytes(ctx, sumsURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to fetch checksums")
+	}
+
+	if len(cfg.PubKeys) > 0 {
+		sigData, err := fetchBytes(ctx, sumsURL+cfg.sigSuffix())
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to fetch checksum signature")
+		}
+
+		if err := VerifyDetachedSignature(sumsData, sigData, cfg.PubKeys); err != nil {
+			return nil, errors.Wrap(err, "checksum signature verification failed")
+		}
+	}
+
+	return ParseSHA256Sums(sumsData)
+}
+
+// verifyChecksum fetches the (optionally signed) checksum manifest for
+// cfg's release and checks that sum (the SHA-256 of the already-downloaded
+// assetName) matches the entry within.
+func verifyChecksum(ctx context.Context, cfg DownloadConfig, assetName string, sum []byte) error {
+	sums, err := fetchSums(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	want, ok := sums[assetName]
+	if !ok {
+		return fmt.Errorf("no checksum entry for %s in %s", assetName, cfg.hashFileSuffix())
+	}
+
+	if got := hex.EncodeToString(sum); !strings.EqualFold(want, got) {
+		return fmt.Errorf("checksum mismatch for %s: want %s, got %s", assetName, want, got)
+	}
+
+	return nil
+}
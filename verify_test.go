@@ -0,0 +1,133 @@
+package upgrade
+
+import (
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestParseSHA256Sums(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "single entry",
+			data: "deadbeef  footool_linux_amd64\n",
+			want: map[string]string{"footool_linux_amd64": "deadbeef"},
+		},
+		{
+			name: "multiple entries, blank lines, and comments",
+			data: "# generated by goreleaser\n" +
+				"AABBCC  footool_linux_amd64\n" +
+				"\n" +
+				"ddeeff  footool_darwin_arm64\n",
+			want: map[string]string{
+				"footool_linux_amd64":  "aabbcc",
+				"footool_darwin_arm64": "ddeeff",
+			},
+		},
+		{
+			name: "binary mode marker is stripped",
+			data: "deadbeef *footool_windows_amd64.exe\n",
+			want: map[string]string{"footool_windows_amd64.exe": "deadbeef"},
+		},
+		{
+			name:    "malformed line",
+			data:    "onlyonefield\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSHA256Sums([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSHA256Sums() expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSHA256Sums() unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseSHA256Sums() = %v, want %v", got, tt.want)
+			}
+			for name, sum := range tt.want {
+				if got[name] != sum {
+					t.Errorf("ParseSHA256Sums()[%q] = %q, want %q", name, got[name], sum)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyDetachedSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	data := []byte("deadbeef  footool_linux_amd64\n")
+	sig := ed25519.Sign(priv, data)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		sig     []byte
+		pubkeys []ed25519.PublicKey
+		wantErr bool
+	}{
+		{
+			name:    "valid signature",
+			data:    data,
+			sig:     sig,
+			pubkeys: []ed25519.PublicKey{pub},
+		},
+		{
+			name:    "valid signature among several keys",
+			data:    data,
+			sig:     sig,
+			pubkeys: []ed25519.PublicKey{otherPub, pub},
+		},
+		{
+			name:    "signature from an unrecognized key",
+			data:    data,
+			sig:     sig,
+			pubkeys: []ed25519.PublicKey{otherPub},
+			wantErr: true,
+		},
+		{
+			name:    "tampered data",
+			data:    []byte("tampered"),
+			sig:     sig,
+			pubkeys: []ed25519.PublicKey{pub},
+			wantErr: true,
+		},
+		{
+			name:    "no public keys configured",
+			data:    data,
+			sig:     sig,
+			pubkeys: nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyDetachedSignature(tt.data, tt.sig, tt.pubkeys)
+			if tt.wantErr && err == nil {
+				t.Fatalf("VerifyDetachedSignature() expected an error, got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("VerifyDetachedSignature() unexpected error: %v", err)
+			}
+		})
+	}
+}
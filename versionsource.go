@@ -0,0 +1,42 @@
+package upgrade
+
+import (
+	"context"
+
+	"github.com/blang/semver"
+)
+
+// VersionSource resolves the latest available version for a given channel,
+// abstracting over how a project publishes its releases. Implementations
+// may also resolve a concrete asset URL (e.g. GitHubVersionSource), in
+// which case Download uses it directly instead of synthesizing one from
+// Repo/Tool/Version.
+type VersionSource interface {
+	Available(ctx context.Context, channel string) (version semver.Version, assetURL string, err error)
+}
+
+// TargetVersionSource is implemented by VersionSource implementations that
+// can resolve a concrete asset URL for a specific, caller-chosen version
+// rather than whatever is latest -- e.g. GitHubVersionSource, which looks
+// up the release tagged for that version. UpgradeContext uses it to honor
+// UpgradeConfig.TargetVersion; VersionSources that don't implement it (e.g.
+// FileVersionSource) leave UpgradeContext to synthesize a URL from
+// Repo/Tool/Version instead, as it always has.
+type TargetVersionSource interface {
+	AvailableTargetVersion(ctx context.Context, version semver.Version) (assetURL string, err error)
+}
+
+// FileVersionSource resolves the available version from a plain-text file
+// at <repo>/<prefix><channel>, the convention AvailableVersion has always
+// used. It never resolves a concrete asset URL, leaving Download to
+// synthesize one as before.
+type FileVersionSource struct {
+	Repo       string
+	FilePrefix string
+}
+
+// Available implements VersionSource.
+func (s FileVersionSource) Available(ctx context.Context, channel string) (semver.Version, string, error) {
+	v, err := AvailableVersionContext(ctx, s.Repo, s.FilePrefix, channel)
+	return v, "", err
+}